@@ -3,11 +3,33 @@
 // This source code is licensed under the MIT license found in the
 // LICENSE file in the root directory of this source tree.
 
+//! Minimal dominating partitions (MDP), the set of values a HashWires commitment is built on.
+
+use crate::errors::HwError;
 use num_bigint::BigUint;
 use num_traits::{Num, One, Zero};
 
-/// Find dominating partition of a string `value` in some input `base` (any base).
-pub(crate) fn find_mdp(value: &BigUint, base: u32) -> Vec<BigUint> {
+/// Find the minimal dominating partition of `value` in some input `base` (any base >= 2).
+///
+/// The partition starts with `value` itself, followed by `(value / base^i) * base^i - 1` for
+/// every `base^i <= value`, i.e. `value` with its lowest `i` digits cleared, minus one. When the
+/// digit at position `i` is non-zero, this decrements it and sets all lower digits to `base - 1`;
+/// when it is zero, the subtraction borrows from the higher digits (e.g. 3409 gives 3399 for
+/// `i = 1` in base 10). Powers for which `value + 1` is a multiple of `base^i` are skipped, as the
+/// lower digits of `value` are already all `base - 1`, and consecutive duplicates are dropped.
+/// Every `x <= value` is then digit-wise dominated by the smallest partition element that is
+/// `>= x`, which is what allows proving any threshold up to `value` with a single chain node per
+/// digit.
+///
+/// The output is sorted in decreasing order.
+///
+/// # Errors
+///
+/// Returns `HwError::InvalidBaseError` if `base` is smaller than 2.
+pub fn find_mdp(value: &BigUint, base: u32) -> Result<Vec<BigUint>, HwError> {
+    if base < 2 {
+        return Err(HwError::InvalidBaseError);
+    }
     let mut exp = BigUint::from(base);
     let mut ret: Vec<BigUint> = Vec::new();
 
@@ -15,7 +37,7 @@ pub(crate) fn find_mdp(value: &BigUint, base: u32) -> Vec<BigUint> {
     ret.push(value.clone());
     let mut prev = value.clone();
 
-    while exp < *value {
+    while exp <= *value {
         // optimizing out the unneeded values to get a minimal dominating partition
         if &val_plus1 % &exp != BigUint::zero() {
             //  (x//b^i - 1) * b^i + (b-1)
@@ -27,7 +49,7 @@ pub(crate) fn find_mdp(value: &BigUint, base: u32) -> Vec<BigUint> {
         }
         exp *= base;
     }
-    ret
+    Ok(ret)
 }
 
 /// This gets the `index`-th value of `msg` split into `bitlength` pieces.
@@ -80,6 +102,9 @@ fn coef(msg: &[u8], index: usize, bitlength: usize) -> u8 {
 
 // TODO: it currently works for bases 2, 4, 16, 256 (bitlength 1, 2, 4, 8) only
 /// Split value, based on base in bitlength, (supports bitlength 1, 2, 4, 8).
+///
+/// Digits are returned most significant first, without leading zeros. Zero is returned as a
+/// single `0` digit, so the output is never empty.
 pub(crate) fn value_split_per_base(value: &BigUint, bitlength: usize) -> Vec<u8> {
     let v_bytes = value.to_bytes_be();
     let v = v_bytes.as_slice();
@@ -92,6 +117,9 @@ pub(crate) fn value_split_per_base(value: &BigUint, bitlength: usize) -> Vec<u8>
             ret.push(coef);
         }
     }
+    if ret.is_empty() {
+        ret.push(0);
+    }
     ret
 }
 
@@ -109,7 +137,7 @@ fn find_dp_u32(value: &str, base: u32) -> Vec<String> {
     ret.push(val.to_str_radix(base));
     // We use prev to detect consecutive duplicate entries (a trick to avoid HashSet)
     let mut prev = val.clone();
-    while exp < val {
+    while exp <= val {
         // optimizing out the unneeded values to get a minimal dominating partition
         if &val_plus1 % &exp != BigUint::zero() {
             //  (x//b^i - 1) * b^i + (b-1)
@@ -145,7 +173,7 @@ fn find_mdp_u32(value: u32, base: u32) -> Vec<u32> {
     let mut ret: Vec<u32> = vec![value];
     let mut prev = value;
 
-    while exp < value {
+    while exp <= value {
         if (value + 1) % exp != 0 {
             let temp = value / exp * exp - 1;
             if prev != temp {
@@ -169,6 +197,8 @@ fn test_mdp_u32() {
     assert_eq!(mdp_u32, vec![3413, 3409, 3399, 2999]);
     let mdp_u32 = find_mdp_u32(9999, 16);
     assert_eq!(mdp_u32, vec![9999, 9983, 8191]);
+    let mdp_u32 = find_mdp_u32(10, 10);
+    assert_eq!(mdp_u32, vec![10, 9]);
     let mdp_u32 = find_mdp_u32(255, 2);
     assert_eq!(mdp_u32, vec![255]);
     let mdp_u32 = find_mdp_u32(254, 2);
@@ -193,6 +223,7 @@ fn test_dp() {
     assert_eq!(to_ints(find_dp_u32("1700", 10)), vec![1700, 1699, 999]);
     assert_eq!(to_ints(find_dp_u32("1000", 10)), vec![1000, 999]);
     assert_eq!(to_ints(find_dp_u32("999", 10)), vec![999]);
+    assert_eq!(to_ints(find_dp_u32("10", 10)), vec![10, 9]);
     assert_eq!(to_ints(find_dp_u32("100099", 10)), vec![100099, 99999]);
 
     // base4
@@ -204,10 +235,10 @@ fn test_dp() {
 }
 
 #[test]
-fn test_mdp() {
+fn test_mdp() -> Result<(), HwError> {
     // base4
     assert_eq!(
-        find_mdp(&BigUint::from_str_radix("312", 4).unwrap(), 4),
+        find_mdp(&BigUint::from_str_radix("312", 4).unwrap(), 4)?,
         vec![
             BigUint::from_str_radix("312", 4).unwrap(),
             BigUint::from_str_radix("303", 4).unwrap(),
@@ -217,7 +248,7 @@ fn test_mdp() {
 
     // base10
     assert_eq!(
-        find_mdp(&BigUint::from(3413u32), 10),
+        find_mdp(&BigUint::from(3413u32), 10)?,
         vec![
             BigUint::from(3413u32),
             BigUint::from(3409u32),
@@ -226,9 +257,28 @@ fn test_mdp() {
         ]
     );
 
+    assert_eq!(
+        find_mdp(&BigUint::from(2999u32), 10)?,
+        vec![BigUint::from(2999u32)]
+    );
+    assert_eq!(
+        find_mdp(&BigUint::from(181u32), 10)?,
+        vec![
+            BigUint::from(181u32),
+            BigUint::from(179u32),
+            BigUint::from(99u32),
+        ]
+    );
+
+    // value equal to the base
+    assert_eq!(
+        find_mdp(&BigUint::from(10u32), 10)?,
+        vec![BigUint::from(10u32), BigUint::from(9u32)]
+    );
+
     // base16
     assert_eq!(
-        find_mdp(&BigUint::from_str_radix("D55", 16).unwrap(), 16),
+        find_mdp(&BigUint::from_str_radix("D55", 16).unwrap(), 16)?,
         vec![
             BigUint::from_str_radix("D55", 16).unwrap(),
             BigUint::from_str_radix("D4F", 16).unwrap(),
@@ -238,7 +288,7 @@ fn test_mdp() {
 
     // base36
     assert_eq!(
-        find_mdp(&BigUint::from_str_radix("2MT", 36).unwrap(), 36),
+        find_mdp(&BigUint::from_str_radix("2MT", 36).unwrap(), 36)?,
         vec![
             BigUint::from_str_radix("2MT", 36).unwrap(),
             BigUint::from_str_radix("2LZ", 36).unwrap(),
@@ -248,13 +298,13 @@ fn test_mdp() {
 
     // base256
     assert_eq!(
-        find_mdp(&BigUint::from_str_radix("65535", 10).unwrap(), 256),
+        find_mdp(&BigUint::from_str_radix("65535", 10).unwrap(), 256)?,
         vec![BigUint::from_str_radix("65535", 10).unwrap()]
     );
 
     // base256 more complex: (256^3 - 7) = 16777209
     assert_eq!(
-        find_mdp(&BigUint::from_str_radix("16777209", 10).unwrap(), 256),
+        find_mdp(&BigUint::from_str_radix("16777209", 10).unwrap(), 256)?,
         vec![
             // 16777209 (decimal) = 1111_1111_1111_1111_1111_1001 (binary)
             BigUint::from_str_radix("16777209", 10).unwrap(),
@@ -264,10 +314,88 @@ fn test_mdp() {
             BigUint::from_str_radix("16711679", 10).unwrap(),
         ]
     );
+
+    // bases 0 and 1 have no dominating partition
+    for base in [0, 1].iter() {
+        assert!(matches!(
+            find_mdp(&BigUint::from(10u32), *base),
+            Err(HwError::InvalidBaseError)
+        ));
+    }
+    Ok(())
+}
+
+#[test]
+fn test_mdp_minimality() -> Result<(), HwError> {
+    use num_traits::ToPrimitive;
+
+    // Whether every digit of `x` is at most the corresponding digit of `s`.
+    fn dominates(mut s: u32, mut x: u32, base: u32) -> bool {
+        while s > 0 || x > 0 {
+            if x % base > s % base {
+                return false;
+            }
+            s /= base;
+            x /= base;
+        }
+        true
+    }
+
+    fn is_dominating_partition(partition: &[u32], value: u32, base: u32) -> bool {
+        (0..=value).all(|x| partition.iter().any(|s| dominates(*s, x, base)))
+    }
+
+    // Brute force search for a dominating partition of `size` elements among `candidates`.
+    fn exists_partition(
+        candidates: &[u32],
+        size: usize,
+        chosen: &mut Vec<u32>,
+        value: u32,
+        base: u32,
+    ) -> bool {
+        if chosen.len() == size {
+            return is_dominating_partition(chosen, value, base);
+        }
+        for (i, c) in candidates.iter().enumerate() {
+            chosen.push(*c);
+            let found = exists_partition(&candidates[i + 1..], size, chosen, value, base);
+            chosen.pop();
+            if found {
+                return true;
+            }
+        }
+        false
+    }
+
+    // Kept small so that the exhaustive search stays fast in CI.
+    for base in [2, 3, 4, 10].iter() {
+        for value in 1..=40u32 {
+            let mdp: Vec<u32> = find_mdp(&BigUint::from(value), *base)?
+                .iter()
+                .map(|v| v.to_u32().unwrap())
+                .collect();
+            assert!(is_dominating_partition(&mdp, value, *base));
+
+            let candidates: Vec<u32> = (0..=value).collect();
+            assert!(!exists_partition(
+                &candidates,
+                mdp.len() - 1,
+                &mut vec![],
+                value,
+                *base
+            ));
+        }
+    }
+    Ok(())
 }
 
 #[test]
 fn test_coef() {
+    // zero is a single digit in every base
+    for bitlength in [1, 2, 4, 8].iter() {
+        assert_eq!(value_split_per_base(&BigUint::zero(), *bitlength), vec![0]);
+    }
+
     // base2 = 2^1
     let number = BigUint::from_str_radix("0010101111010101", 2).unwrap();
     let splits = value_split_per_base(&number, 1);
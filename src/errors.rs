@@ -18,10 +18,20 @@ pub enum HwError {
     SeedLengthError,
     /// Verification of proof failed
     ProofVerificationError,
+    /// Proof does not contain one chain node per digit of the threshold
+    MalformedProofError,
     /// Error in decoding merkle proof
     MerkleProofDecodingError,
     /// Proving value is bigger than the issued value
     MdpError,
     /// Error in serializing / deserializing bytestrings
     SerializationError,
+    /// Base is not supported, only bases 2, 4, 16 and 256 are currently allowed
+    UnsupportedBaseError,
+    /// Base of a dominating partition must be at least 2
+    InvalidBaseError,
+    /// max_number_bits is smaller than a single digit in the selected base
+    MaxNumberBitsError,
+    /// Value does not fit in max_number_bits
+    ValueTooLargeError,
 }
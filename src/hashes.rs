@@ -150,6 +150,24 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_hash_chain_keccak256() {
+        use sha3::Keccak256;
+
+        // keccak256(bytes32(0)), matching Solidity's keccak256(abi.encode(uint256(0))).
+        let hash_chain_output = hash_chain::<Keccak256>(&[0u8; 32], 1);
+        assert_eq!(
+            hex::encode(hash_chain_output),
+            "290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e563"
+        );
+
+        let hash_chain_output = hash_chain::<Keccak256>(&[0u8; 32], 3);
+        assert_eq!(
+            hex::encode(hash_chain_output),
+            "356e5a2cc1eba076e650ac7473fccc37952b46bc2e419a200cec0c451dce2336"
+        );
+    }
+
     #[test]
     fn test_full_hash_chain() {
         use blake3::Hasher as Blake3;
@@ -171,6 +189,23 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_hash_chain_matches_full_hash_chain() {
+        let seed = b"01234567890123456789012345678901";
+
+        // Zero iterations return the seed itself.
+        assert_eq!(&hash_chain::<Blake3>(seed, 0), seed);
+
+        let chain = full_hash_chain::<Blake3>(seed, 256);
+        for n in [1, 2, 3, 15, 255].iter() {
+            assert_eq!(hash_chain::<Blake3>(seed, *n), chain[*n]);
+        }
+        assert_eq!(
+            hex::encode(chain[3]),
+            "9dce6dd3c7e70a6e5052fe1626b97d5ff50f59764513950df43faf76f15efc5c"
+        );
+    }
+
     #[test]
     fn test_compute_hashchains() {
         let seed = [0u8; 32];
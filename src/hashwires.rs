@@ -11,6 +11,7 @@ use generic_array::{
     GenericArray,
 };
 use num_bigint::BigUint;
+use num_traits::One;
 
 use crate::dp::{find_mdp, value_split_per_base};
 use crate::errors::HwError;
@@ -18,7 +19,7 @@ use crate::hashes::{
     compute_hash_chains, generate_subseeds, hash_chain, plr_accumulator, salted_hash,
     SMTREE_PADDING_SALT, TOP_SALT,
 };
-use crate::serialization::{serialize, take_slice, tokenize};
+use crate::serialization::{i2osp, os2ip, serialize, take_slice, tokenize};
 use crate::shuffle::deterministic_index_shuffling;
 use crate::traits::Hash;
 use smtree::index::TreeIndex;
@@ -37,6 +38,9 @@ pub(crate) type ChainNodesSize = U32;
 pub(crate) type MdpSaltSize = U16;
 pub(crate) type SmtSecretSize = U32;
 
+// Number of bytes used to encode the base in a serialized commitment.
+const BASE_SIZE: usize = 4;
+
 /// HashWires commitment structure.
 pub struct Commitment<D: Hash> {
     base: u32,
@@ -63,7 +67,10 @@ impl<D: Hash> Secret<D> {
 
     /// Generate a HashWires commitment.
     pub fn commit(&self, base: u32, max_number_bits: usize) -> Result<Commitment<D>, HwError> {
-        let mdp_smt_height = compute_mdp_height(base, max_number_bits);
+        if self.value > max_value(base, max_number_bits)? {
+            return Err(HwError::ValueTooLargeError);
+        }
+        let mdp_smt_height = compute_mdp_height(base, max_number_bits)?;
         let commitment = commit_gen::<D>(
             &self.value,
             base,
@@ -85,7 +92,11 @@ impl<D: Hash> Secret<D> {
         max_number_bits: usize,
         threshold: &BigUint,
     ) -> Result<Proof, HwError> {
-        let mdp_smt_height = compute_mdp_height(base, max_number_bits);
+        let max = max_value(base, max_number_bits)?;
+        if self.value > max || threshold > &max {
+            return Err(HwError::ValueTooLargeError);
+        }
+        let mdp_smt_height = compute_mdp_height(base, max_number_bits)?;
         let result = larger_than_proof_gen::<D>(
             threshold,
             &self.value,
@@ -121,18 +132,26 @@ impl<D: Hash> Commitment<D> {
         }
     }
 
-    /// Serialize a HashWires commitment.
+    /// Serialize a HashWires commitment, prefixed by its base so that the base is covered by
+    /// any signature over the serialized bytes.
     pub fn serialize(&self) -> Vec<u8> {
-        self.commitment.clone()
+        [
+            &i2osp(self.base as usize, BASE_SIZE)[..],
+            &self.commitment[..],
+        ]
+        .concat()
     }
 
     /// Deserialize a HashWires commitment.
-    pub fn deserialize(bytes: &[u8], base: u32) -> Self {
-        Self {
+    pub fn deserialize(bytes: &[u8]) -> Result<Self, HwError> {
+        let (base, commitment) = take_slice(bytes, BASE_SIZE)?;
+        let base = os2ip(base)? as u32;
+        compute_bitlength(base)?;
+        Ok(Self {
             base,
-            commitment: bytes.to_vec(),
+            commitment: commitment.to_vec(),
             _d: PhantomData,
-        }
+        })
     }
 }
 
@@ -180,17 +199,14 @@ impl Proof {
             }
         };
 
-        let mut chain_nodes = vec![];
-        let mut cn_index = 0;
-        while cn_index < chain_nodes_flattened.len() {
-            chain_nodes.push(GenericArray::clone_from_slice(
-                &chain_nodes_flattened[cn_index..cn_index + ChainNodesSize::to_usize()],
-            ));
-            cn_index += ChainNodesSize::to_usize();
-        }
-        if cn_index != chain_nodes_flattened.len() {
+        // Reject chain nodes that are not a whole number of ChainNodesSize hashes.
+        if chain_nodes_flattened.len() % ChainNodesSize::to_usize() != 0 {
             return Err(HwError::SerializationError);
         }
+        let chain_nodes = chain_nodes_flattened
+            .chunks_exact(ChainNodesSize::to_usize())
+            .map(GenericArray::clone_from_slice)
+            .collect();
 
         Ok(Self {
             chain_nodes,
@@ -201,6 +217,14 @@ impl Proof {
     }
 }
 
+/// Maximum value that can be committed to, or proven, in `base` using `max_number_bits`.
+///
+/// Only whole digits fit in `max_number_bits`, so this is `base^(max_number_bits / bitlength) - 1`.
+pub fn max_value(base: u32, max_number_bits: usize) -> Result<BigUint, HwError> {
+    let max_digits = compute_max_digits(base, max_number_bits)?;
+    Ok(num_traits::pow(BigUint::from(base), max_digits) - BigUint::one())
+}
+
 /// Generate larger than proof.
 #[allow(clippy::type_complexity)]
 pub fn larger_than_proof_gen<D: Hash>(
@@ -221,10 +245,10 @@ pub fn larger_than_proof_gen<D: Hash>(
     HwError,
 > {
     // Step 0: compute base's bitlength
-    let bitlength = compute_bitlength(base);
+    let bitlength = compute_bitlength(base)?;
 
     // Step 1: find MDP
-    let mdp: Vec<BigUint> = find_mdp(value, base);
+    let mdp: Vec<BigUint> = find_mdp(value, base)?;
 
     // Step 2: split MDP values per base (bitlength digits)
     let splits: Vec<Vec<u8>> = mdp_splits(&mdp, bitlength);
@@ -296,8 +320,12 @@ pub fn proof_verify<D: Hash>(
     mdp_salt: &GenericArray<u8, MdpSaltSize>,
     smt_inclusion_proof: &[u8],
 ) -> Result<bool, HwError> {
-    let bitlength = compute_bitlength(base);
+    let bitlength = compute_bitlength(base)?;
     let requested_value_split = value_split_per_base(proving_value, bitlength);
+    // A proof carries exactly one chain node per digit of the threshold.
+    if chain_nodes.len() != requested_value_split.len() {
+        return Err(HwError::MalformedProofError);
+    }
     let mdp_chain_nodes: Vec<[u8; 32]> = chain_nodes
         .iter()
         .enumerate()
@@ -343,10 +371,10 @@ pub fn commit_gen<D: Hash>(
     mdp_smt_height: usize,
 ) -> Result<Vec<u8>, HwError> {
     // Step 0: compute base's bitlength
-    let bitlength = compute_bitlength(base);
+    let bitlength = compute_bitlength(base)?;
 
     // Step 1: find MDP
-    let mdp: Vec<BigUint> = find_mdp(value, base);
+    let mdp: Vec<BigUint> = find_mdp(value, base)?;
 
     // Step 2: split MDP values per base (bitlength digits)
     let splits: Vec<Vec<u8>> = mdp_splits(&mdp, bitlength);
@@ -433,13 +461,13 @@ fn pick_mdp_index(proving_value: &BigUint, mdp: &[BigUint]) -> Result<usize, HwE
 }
 
 // Compute base's bitlength.
-fn compute_bitlength(base: u32) -> usize {
+fn compute_bitlength(base: u32) -> Result<usize, HwError> {
     match base {
-        2 => 1,
-        4 => 2,
-        16 => 4,
-        256 => 8,
-        _ => panic!(),
+        2 => Ok(1),
+        4 => Ok(2),
+        16 => Ok(4),
+        256 => Ok(8),
+        _ => Err(HwError::UnsupportedBaseError),
     }
 }
 
@@ -562,8 +590,20 @@ fn log_2(x: u32) -> u32 {
     num_bits::<u32>() as u32 - x.leading_zeros() - 1
 }
 
-fn compute_mdp_height(base: u32, max_number_bits: usize) -> u32 {
-    log_2(max_number_bits as u32 / log_2(base))
+// Number of whole digits in base that fit in max_number_bits.
+fn compute_max_digits(base: u32, max_number_bits: usize) -> Result<usize, HwError> {
+    let bitlength = compute_bitlength(base)?;
+    let max_digits = max_number_bits / bitlength;
+    if max_digits == 0 {
+        return Err(HwError::MaxNumberBitsError);
+    }
+    Ok(max_digits)
+}
+
+// Height of the SMT holding the shuffled MDP roots, which requires one leaf per digit.
+fn compute_mdp_height(base: u32, max_number_bits: usize) -> Result<u32, HwError> {
+    let max_digits = compute_max_digits(base, max_number_bits)?;
+    Ok(log_2(max_digits.next_power_of_two() as u32))
 }
 
 #[cfg(test)]
@@ -571,12 +611,14 @@ mod tests {
     use super::*;
     use blake3::Hasher as Blake3;
     use num_traits::{FromPrimitive, Num};
+    use rand::SeedableRng;
+    use rand_chacha::ChaCha12Rng;
     use rand_core::{OsRng, RngCore};
     use smtree::pad_secret::ALL_ZEROS_SECRET;
     use smtree::utils::print_output;
 
     // A full HashWires cycle with serialized outputs.
-    fn prove_and_verify(
+    fn prove_and_verify<D: Hash>(
         base: u32,
         max_number_bits: usize,
         value: &BigUint,
@@ -586,9 +628,19 @@ mod tests {
         let mut rng = OsRng;
         let mut seed = vec![0u8; 32];
         rng.fill_bytes(&mut seed);
+        prove_and_verify_with_seed::<D>(&seed, base, max_number_bits, value, threshold)
+    }
 
+    // A full HashWires cycle with serialized outputs, for a given secret seed.
+    fn prove_and_verify_with_seed<D: Hash>(
+        seed: &[u8],
+        base: u32,
+        max_number_bits: usize,
+        value: &BigUint,
+        threshold: &BigUint,
+    ) -> Result<(), HwError> {
         // Generate secret.
-        let secret = Secret::<Blake3>::gen(&seed, &value);
+        let secret = Secret::<D>::gen(seed, &value);
 
         // Generate and serialize commitment.
         let commitment = secret.commit(base, max_number_bits)?;
@@ -600,15 +652,30 @@ mod tests {
 
         // Verify a range proof over a commitment.
         commitment.verify(&proof, &threshold)?;
-        Commitment::<Blake3>::deserialize(&commitment_bytes, base)
+        Commitment::<D>::deserialize(&commitment_bytes)?
             .verify(&Proof::deserialize(&proof_bytes)?, &threshold)
     }
 
+    // A commitment to 402 in base 4 over 32 bits, with a proof for threshold 378, shared by the
+    // tests that exercise verification and parameter errors.
+    fn commit_and_prove() -> Result<(Secret<Blake3>, Commitment<Blake3>, Proof, BigUint), HwError> {
+        let seed = [0u8; 32];
+        let value = BigUint::from_u32(402).unwrap();
+        let threshold = BigUint::from_u32(378).unwrap();
+        let secret = Secret::<Blake3>::gen(&seed, &value);
+        let commitment = secret.commit(4, 32)?;
+        let proof = secret.prove(4, 32, &threshold)?;
+        Ok((secret, commitment, proof, threshold))
+    }
+
     #[test]
     fn test_proof_success() -> Result<(), HwError> {
         let value = BigUint::from_u32(402).unwrap();
         let threshold = BigUint::from_u32(378).unwrap();
-        assert_eq!(true, prove_and_verify(4, 32, &value, &threshold).is_ok());
+        assert_eq!(
+            true,
+            prove_and_verify::<Blake3>(4, 32, &value, &threshold).is_ok()
+        );
         Ok(())
     }
 
@@ -616,7 +683,453 @@ mod tests {
     fn test_proof_failure() -> Result<(), HwError> {
         let value = BigUint::from_u32(378).unwrap();
         let threshold = BigUint::from_u32(402).unwrap();
-        assert_eq!(true, prove_and_verify(4, 32, &value, &threshold).is_err());
+        assert_eq!(
+            true,
+            prove_and_verify::<Blake3>(4, 32, &value, &threshold).is_err()
+        );
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_boundaries() -> Result<(), HwError> {
+        let value = BigUint::from_u32(1000).unwrap();
+        for base in [2u32, 4, 16, 256].iter() {
+            for threshold in [0u32, 1, 999, 1000].iter() {
+                let threshold = BigUint::from_u32(*threshold).unwrap();
+                assert!(prove_and_verify::<Blake3>(*base, 32, &value, &threshold).is_ok());
+            }
+            let threshold = BigUint::from_u32(1001).unwrap();
+            assert!(prove_and_verify::<Blake3>(*base, 32, &value, &threshold).is_err());
+        }
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_zero_value() -> Result<(), HwError> {
+        let value = BigUint::from_u32(0).unwrap();
+        for base in [2u32, 4, 16, 256].iter() {
+            let threshold = BigUint::from_u32(0).unwrap();
+            assert!(prove_and_verify::<Blake3>(*base, 32, &value, &threshold).is_ok());
+            let threshold = BigUint::from_u32(1).unwrap();
+            assert!(prove_and_verify::<Blake3>(*base, 32, &value, &threshold).is_err());
+        }
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_value_equal_to_base() -> Result<(), HwError> {
+        for base in [2u32, 4, 16, 256].iter() {
+            let value = BigUint::from(*base);
+            let threshold = BigUint::from(*base - 1);
+            assert!(prove_and_verify::<Blake3>(*base, 32, &value, &threshold).is_ok());
+        }
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_sha256() -> Result<(), HwError> {
+        use sha2::Sha256;
+
+        let value = BigUint::from_u32(402).unwrap();
+        let threshold = BigUint::from_u32(378).unwrap();
+        assert!(prove_and_verify::<Sha256>(16, 32, &value, &threshold).is_ok());
+        assert!(prove_and_verify::<Sha256>(16, 32, &threshold, &value).is_err());
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_keccak256() -> Result<(), HwError> {
+        use sha3::Keccak256;
+
+        let value = BigUint::from_u32(402).unwrap();
+        let threshold = BigUint::from_u32(378).unwrap();
+        assert!(prove_and_verify::<Keccak256>(256, 32, &value, &threshold).is_ok());
+        assert!(prove_and_verify::<Keccak256>(256, 32, &threshold, &value).is_err());
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_threshold_digits_mismatch() -> Result<(), HwError> {
+        let (_, commitment, proof, _) = commit_and_prove()?;
+
+        // Verifying against thresholds with fewer digits than the proof must fail, not panic.
+        for smaller in [0u32, 3, 63].iter() {
+            let smaller = BigUint::from_u32(*smaller).unwrap();
+            assert!(matches!(
+                commitment.verify(&proof, &smaller),
+                Err(HwError::MalformedProofError)
+            ));
+        }
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_threshold_more_digits() -> Result<(), HwError> {
+        let (_, commitment, proof, threshold) = commit_and_prove()?;
+
+        // 378 is "11322" in base 4, and 1512 = 378 * 4 is "113220". The chain nodes proving 378
+        // hash to the commitment for the leading digits of 1512, which used to be accepted.
+        for larger in [1512u32, 6048].iter() {
+            let larger = BigUint::from_u32(*larger).unwrap();
+            assert!(larger > threshold);
+            assert!(matches!(
+                commitment.verify(&proof, &larger),
+                Err(HwError::MalformedProofError)
+            ));
+        }
+        Ok(())
+    }
+
+    #[test]
+    fn test_verification_errors() -> Result<(), HwError> {
+        let (secret, commitment, proof, threshold) = commit_and_prove()?;
+        assert!(commitment.verify(&proof, &threshold).is_ok());
+
+        // The threshold is not dominated by any MDP element. The prover detects this, but the
+        // issued value is hidden from the verifier, for which it is a proof that does not verify.
+        let larger = BigUint::from_u32(403).unwrap();
+        assert!(matches!(
+            secret.prove(4, 32, &larger),
+            Err(HwError::MdpError)
+        ));
+        assert!(matches!(
+            commitment.verify(&proof, &larger),
+            Err(HwError::ProofVerificationError)
+        ));
+
+        // Well-formed, but the hashes do not reconstruct the commitment.
+        let other = BigUint::from_u32(377).unwrap();
+        assert!(matches!(
+            commitment.verify(&proof, &other),
+            Err(HwError::ProofVerificationError)
+        ));
+
+        // The SMT inclusion proof cannot be decoded.
+        let undecodable = Proof {
+            plr_padding: proof.plr_padding,
+            chain_nodes: proof.chain_nodes.clone(),
+            mdp_salt: proof.mdp_salt,
+            smt_inclusion_proof: vec![],
+        };
+        assert!(matches!(
+            commitment.verify(&undecodable, &threshold),
+            Err(HwError::MerkleProofDecodingError)
+        ));
+
+        // The commitment declares an unsupported base.
+        let unsupported = Commitment::<Blake3> {
+            base: 10,
+            commitment: commitment.commitment.clone(),
+            _d: PhantomData,
+        };
+        assert!(matches!(
+            unsupported.verify(&proof, &threshold),
+            Err(HwError::UnsupportedBaseError)
+        ));
+
+        // MalformedProofError is covered by test_proof_threshold_digits_mismatch and
+        // test_proof_threshold_more_digits.
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_base_mismatch() -> Result<(), HwError> {
+        let seed = [0u8; 32];
+        let value = BigUint::from_u32(10).unwrap();
+        let secret = Secret::<Blake3>::gen(&seed, &value);
+        let commitment_bytes = secret.commit(4, 32)?.serialize();
+        assert_eq!(&commitment_bytes[..BASE_SIZE], &[0, 0, 0, 4]);
+        let commitment = Commitment::<Blake3>::deserialize(&commitment_bytes)?;
+
+        let five = BigUint::from_u32(5).unwrap();
+        let seventeen = BigUint::from_u32(17).unwrap();
+        let proof = secret.prove(4, 32, &five)?;
+        assert!(commitment.verify(&proof, &five).is_ok());
+        assert!(commitment.verify(&proof, &seventeen).is_err());
+
+        // 5 is "11" in base 4 and 17 is "11" in base 16, so the chain nodes proving 5 in base 4
+        // also prove 17 once the same root is read as a base 16 commitment. Only the base prefix
+        // prevents this, which is why the issuer's signature must cover the serialized bytes.
+        let mut forged_bytes = commitment_bytes.clone();
+        forged_bytes[..BASE_SIZE].copy_from_slice(&16u32.to_be_bytes());
+        let forged = Commitment::<Blake3>::deserialize(&forged_bytes)?;
+        assert!(forged.verify(&proof, &seventeen).is_ok());
+        Ok(())
+    }
+
+    // Number of iterations of the randomized tests (random values per base, or batches of
+    // mutated proofs), which can be raised locally through the HW_TEST_ITERATIONS environment
+    // variable.
+    fn test_iterations() -> usize {
+        std::env::var("HW_TEST_ITERATIONS")
+            .ok()
+            .and_then(|v| v.parse().ok())
+            .unwrap_or(10)
+    }
+
+    // Deterministic RNG for the randomized tests, so that a failure can be reproduced by setting
+    // the HW_TEST_SEED environment variable to the seed reported in the assertion message.
+    fn test_rng() -> (u64, ChaCha12Rng) {
+        let seed = std::env::var("HW_TEST_SEED")
+            .ok()
+            .and_then(|v| v.parse().ok())
+            .unwrap_or(0);
+        (seed, ChaCha12Rng::seed_from_u64(seed))
+    }
+
+    // A random 32-byte secret seed.
+    fn random_seed(rng: &mut ChaCha12Rng) -> Vec<u8> {
+        let mut seed = vec![0u8; 32];
+        rng.fill_bytes(&mut seed);
+        seed
+    }
+
+    #[test]
+    fn test_random_proofs() -> Result<(), HwError> {
+        let (rng_seed, mut rng) = test_rng();
+        for base in [2, 4, 16, 256].iter() {
+            for _ in 0..test_iterations() {
+                let seed = random_seed(&mut rng);
+                let value = BigUint::from(rng.next_u32());
+
+                // Any threshold in [0, value] must be provable.
+                let threshold = BigUint::from(rng.next_u32()) % (&value + 1u32);
+                assert!(
+                    prove_and_verify_with_seed::<Blake3>(&seed, *base, 32, &value, &threshold)
+                        .is_ok(),
+                    "HW_TEST_SEED={}",
+                    rng_seed
+                );
+
+                // Any threshold above value must not be, neither by the prover nor by a verifier
+                // given an honest proof for a threshold up to value.
+                let secret = Secret::<Blake3>::gen(&seed, &value);
+                let commitment = secret.commit(*base, 32)?;
+                let proof = secret.prove(*base, 32, &threshold)?;
+                let larger = [
+                    &value + 1u32 + rng.next_u32() % 1000,
+                    // Appends a zero digit, keeping the proven digits as a prefix.
+                    &threshold * *base,
+                ];
+                for larger in larger.iter().filter(|x| *x > &value) {
+                    assert!(
+                        prove_and_verify_with_seed::<Blake3>(&seed, *base, 32, &value, larger)
+                            .is_err(),
+                        "HW_TEST_SEED={}",
+                        rng_seed
+                    );
+                    assert!(
+                        commitment.verify(&proof, larger).is_err(),
+                        "HW_TEST_SEED={}",
+                        rng_seed
+                    );
+                }
+            }
+        }
+        Ok(())
+    }
+
+    // Decode and verify a possibly malformed proof, which must never panic. A mutated proof may
+    // only verify if the mutation did not touch the chain nodes, MDP salt or PLR padding.
+    fn check_malformed_proof(
+        commitment: &Commitment<Blake3>,
+        original: &Proof,
+        bytes: &[u8],
+        threshold: &BigUint,
+        rng_seed: u64,
+    ) {
+        if let Ok(proof) = Proof::deserialize(bytes) {
+            if commitment.verify(&proof, threshold).is_ok() {
+                assert_eq!(
+                    proof.chain_nodes, original.chain_nodes,
+                    "HW_TEST_SEED={}",
+                    rng_seed
+                );
+                assert_eq!(
+                    proof.mdp_salt, original.mdp_salt,
+                    "HW_TEST_SEED={}",
+                    rng_seed
+                );
+                assert_eq!(
+                    proof.plr_padding, original.plr_padding,
+                    "HW_TEST_SEED={}",
+                    rng_seed
+                );
+            }
+        }
+    }
+
+    #[test]
+    fn test_malformed_proofs() -> Result<(), HwError> {
+        let (rng_seed, mut rng) = test_rng();
+        let (_, commitment, proof, threshold) = commit_and_prove()?;
+        let proof_bytes = proof.serialize();
+        assert!(proof.plr_padding.is_some());
+
+        // Every truncation.
+        for len in 0..proof_bytes.len() {
+            check_malformed_proof(
+                &commitment,
+                &proof,
+                &proof_bytes[..len],
+                &threshold,
+                rng_seed,
+            );
+        }
+
+        // A single bit flip in every byte, including the SMT inclusion proof and PLR padding.
+        for i in 0..proof_bytes.len() {
+            for bit in 0..8 {
+                let mut bytes = proof_bytes.clone();
+                bytes[i] ^= 1 << bit;
+                check_malformed_proof(&commitment, &proof, &bytes, &threshold, rng_seed);
+            }
+        }
+
+        // Several random byte changes per buffer, optionally truncated or extended.
+        for _ in 0..test_iterations() * 100 {
+            let mut bytes = proof_bytes.clone();
+            for _ in 0..1 + rng.next_u32() % 8 {
+                let i = rng.next_u32() as usize % bytes.len();
+                bytes[i] ^= 1 + (rng.next_u32() % 255) as u8;
+            }
+            match rng.next_u32() % 3 {
+                0 => bytes.truncate(rng.next_u32() as usize % bytes.len()),
+                1 => bytes.push(rng.next_u32() as u8),
+                _ => {}
+            }
+            check_malformed_proof(&commitment, &proof, &bytes, &threshold, rng_seed);
+        }
+        Ok(())
+    }
+
+    #[test]
+    fn test_value_too_large() -> Result<(), HwError> {
+        let seed = [0u8; 32];
+        assert_eq!(max_value(4, 32)?, BigUint::from(u32::MAX));
+        assert_eq!(max_value(256, 64)?, BigUint::from(u64::MAX));
+
+        let value = max_value(4, 32)? + 1u32;
+        let secret = Secret::<Blake3>::gen(&seed, &value);
+        assert!(matches!(
+            secret.commit(4, 32),
+            Err(HwError::ValueTooLargeError)
+        ));
+
+        let value = BigUint::from_u32(402).unwrap();
+        let threshold = max_value(4, 32)? + 1u32;
+        let secret = Secret::<Blake3>::gen(&seed, &value);
+        assert!(matches!(
+            secret.prove(4, 32, &threshold),
+            Err(HwError::ValueTooLargeError)
+        ));
+        Ok(())
+    }
+
+    #[test]
+    fn test_value_too_large_partial_digit() -> Result<(), HwError> {
+        // Bits that do not make up a whole digit of the base are unusable.
+        assert_eq!(max_value(16, 6)?, BigUint::from_u32(15).unwrap());
+        assert_eq!(max_value(4, 7)?, BigUint::from_u32(63).unwrap());
+        assert_eq!(max_value(16, 10)?, BigUint::from_u32(255).unwrap());
+
+        // 10 bits hold two base 16 digits, so 256..=1023 must be rejected.
+        let seed = [0u8; 32];
+        for value in [256u32, 1023].iter() {
+            let secret = Secret::<Blake3>::gen(&seed, &BigUint::from(*value));
+            assert!(matches!(
+                secret.commit(16, 10),
+                Err(HwError::ValueTooLargeError)
+            ));
+        }
+
+        let value = BigUint::from_u32(255).unwrap();
+        let threshold = BigUint::from_u32(200).unwrap();
+        assert!(prove_and_verify::<Blake3>(16, 10, &value, &threshold).is_ok());
+        let threshold = BigUint::from_u32(256).unwrap();
+        let secret = Secret::<Blake3>::gen(&seed, &value);
+        assert!(matches!(
+            secret.prove(16, 10, &threshold),
+            Err(HwError::ValueTooLargeError)
+        ));
+        Ok(())
+    }
+
+    #[test]
+    fn test_unsupported_base() -> Result<(), HwError> {
+        let (secret, _, _, threshold) = commit_and_prove()?;
+
+        for base in [0, 1, 3, 10, 257].iter() {
+            assert!(matches!(
+                secret.commit(*base, 32),
+                Err(HwError::UnsupportedBaseError)
+            ));
+            assert!(matches!(
+                secret.prove(*base, 32, &threshold),
+                Err(HwError::UnsupportedBaseError)
+            ));
+        }
+
+        // A commitment declaring an unsupported base must not deserialize either.
+        let mut commitment_bytes = secret.commit(4, 32)?.serialize();
+        commitment_bytes[..BASE_SIZE].copy_from_slice(&10u32.to_be_bytes());
+        assert!(matches!(
+            Commitment::<Blake3>::deserialize(&commitment_bytes),
+            Err(HwError::UnsupportedBaseError)
+        ));
+        Ok(())
+    }
+
+    #[test]
+    fn test_max_number_bits_too_small() -> Result<(), HwError> {
+        let seed = [0u8; 32];
+        let value = BigUint::from_u32(3).unwrap();
+        let secret = Secret::<Blake3>::gen(&seed, &value);
+
+        assert!(matches!(
+            secret.commit(256, 4),
+            Err(HwError::MaxNumberBitsError)
+        ));
+        assert!(matches!(
+            secret.prove(256, 4, &value),
+            Err(HwError::MaxNumberBitsError)
+        ));
+        Ok(())
+    }
+
+    #[test]
+    fn test_mdp_height() -> Result<(), HwError> {
+        assert_eq!(compute_mdp_height(4, 32)?, 4);
+        assert_eq!(compute_mdp_height(256, 64)?, 3);
+        assert_eq!(compute_mdp_height(16, 4)?, 0);
+
+        // Digit counts that are not a power of two round the height up.
+        assert_eq!(compute_mdp_height(4, 24)?, 4);
+        assert_eq!(compute_mdp_height(16, 12)?, 2);
+        assert_eq!(compute_mdp_height(2, 24)?, 5);
+
+        // Shuffled indexes range over every digit, so all values must still round trip.
+        let (rng_seed, mut rng) = test_rng();
+        for (base, max_number_bits) in [(4u32, 24usize), (16, 12), (2, 24)].iter() {
+            let max = max_value(*base, *max_number_bits)?;
+            for _ in 0..test_iterations() {
+                let seed = random_seed(&mut rng);
+                let value = BigUint::from(rng.next_u32()) % (&max + 1u32);
+                let threshold = BigUint::from(rng.next_u32()) % (&value + 1u32);
+                assert!(
+                    prove_and_verify_with_seed::<Blake3>(
+                        &seed,
+                        *base,
+                        *max_number_bits,
+                        &value,
+                        &threshold
+                    )
+                    .is_ok(),
+                    "HW_TEST_SEED={}",
+                    rng_seed
+                );
+            }
+        }
         Ok(())
     }
 
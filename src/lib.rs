@@ -3,7 +3,7 @@
 // This source code is licensed under the MIT license found in the
 // LICENSE file in the root directory of this source tree.
 
-mod dp;
+pub mod dp;
 mod hashes;
 pub mod hashwires;
 mod serialization;
@@ -74,13 +74,43 @@ mod tests {
     #[test]
     fn test_commit_serialization() -> Result<(), HwError> {
         let mut rng = OsRng;
-        let mut bytes = [0u8; 32];
+        let mut bytes = [0u8; 36];
         rng.fill_bytes(&mut bytes);
+        bytes[..4].copy_from_slice(&[0, 0, 0, 4]);
 
-        let commitment = Commitment::<Blake3>::deserialize(&bytes, 4);
+        let commitment = Commitment::<Blake3>::deserialize(&bytes)?;
         let output = commitment.serialize();
 
         assert_eq!(bytes.to_vec(), output);
+
+        // Too short to hold the base.
+        assert!(Commitment::<Blake3>::deserialize(&bytes[..3]).is_err());
+        Ok(())
+    }
+
+    #[test]
+    fn test_proof_deserialization_bad_lengths() -> Result<(), HwError> {
+        // Chain nodes whose length is not a multiple of ChainNodesSize.
+        let chain_nodes_flattened = vec![0u8; crate::hashwires::ChainNodesSize::to_usize() + 1];
+        let mdp_salt = vec![0u8; crate::hashwires::MdpSaltSize::to_usize()];
+        let smt_inclusion_proof = [0u8; 32];
+        let bytes = [
+            &serialize(&chain_nodes_flattened, 2),
+            &mdp_salt[..],
+            &serialize(&smt_inclusion_proof, 2),
+        ]
+        .concat();
+        assert!(Proof::deserialize(&bytes).is_err());
+
+        // Truncated PLR padding.
+        let mut bytes = sample_dummy_proof_bytes(true);
+        bytes.pop();
+        assert!(Proof::deserialize(&bytes).is_err());
+
+        // Trailing bytes after the PLR padding.
+        let mut bytes = sample_dummy_proof_bytes(true);
+        bytes.push(0);
+        assert!(Proof::deserialize(&bytes).is_err());
         Ok(())
     }
 